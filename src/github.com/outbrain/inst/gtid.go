@@ -0,0 +1,262 @@
+package inst
+
+import (
+	"fmt"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GTIDInterval is a single, inclusive range of transaction sequence numbers
+// generated by one server UUID, e.g. the "1-100" part of "uuid:1-100".
+type GTIDInterval struct {
+	Start	int64
+	End		int64
+}
+
+func (this *GTIDInterval) String() string {
+	if this.Start == this.End {
+		return strconv.FormatInt(this.Start, 10)
+	}
+	return fmt.Sprintf("%d-%d", this.Start, this.End)
+}
+
+func (this *GTIDInterval) Contains(other *GTIDInterval) bool {
+	return this.Start <= other.Start && this.End >= other.End
+}
+
+type intervalsByStart []GTIDInterval
+
+func (this intervalsByStart) Len() int           { return len(this) }
+func (this intervalsByStart) Swap(i, j int)      { this[i], this[j] = this[j], this[i] }
+func (this intervalsByStart) Less(i, j int) bool { return this[i].Start < this[j].Start }
+
+// GTIDSet maps a server UUID onto the (sorted, non-overlapping) intervals of
+// transactions that UUID has generated, mirroring MySQL's own
+// "uuid:1-100,uuid:200-300" textual representation.
+type GTIDSet map[string][]GTIDInterval
+
+// ParseGTIDSet parses the standard "uuid:1-100,uuid:1-50:60-70" textual form
+// produced by @@gtid_executed, @@gtid_purged and Retrieved_Gtid_Set.
+func ParseGTIDSet(gtidSetString string) (GTIDSet, error) {
+	gtidSet := GTIDSet{}
+	gtidSetString = strings.TrimSpace(gtidSetString)
+	if gtidSetString == "" {
+		return gtidSet, nil
+	}
+	for _, uuidSet := range strings.Split(gtidSetString, ",") {
+		uuidSet = strings.TrimSpace(uuidSet)
+		if uuidSet == "" {
+			continue
+		}
+		tokens := strings.Split(uuidSet, ":")
+		if len(tokens) < 2 {
+			return nil, errors.New(fmt.Sprintf("inst.ParseGTIDSet: malformed uuid set %+v", uuidSet))
+		}
+		uuid := strings.ToLower(tokens[0])
+		intervals := []GTIDInterval{}
+		for _, intervalToken := range tokens[1:] {
+			interval, err := parseGTIDInterval(intervalToken)
+			if err != nil {
+				return nil, err
+			}
+			intervals = append(intervals, interval)
+		}
+		gtidSet[uuid] = mergeIntervals(append(gtidSet[uuid], intervals...))
+	}
+	return gtidSet, nil
+}
+
+func parseGTIDInterval(intervalToken string) (GTIDInterval, error) {
+	intervalToken = strings.TrimSpace(intervalToken)
+	rangeTokens := strings.Split(intervalToken, "-")
+	start, err := strconv.ParseInt(rangeTokens[0], 10, 64)
+	if err != nil {
+		return GTIDInterval{}, errors.New(fmt.Sprintf("inst.ParseGTIDSet: malformed interval %+v", intervalToken))
+	}
+	end := start
+	if len(rangeTokens) == 2 {
+		end, err = strconv.ParseInt(rangeTokens[1], 10, 64)
+		if err != nil {
+			return GTIDInterval{}, errors.New(fmt.Sprintf("inst.ParseGTIDSet: malformed interval %+v", intervalToken))
+		}
+	}
+	return GTIDInterval{Start: start, End: end}, nil
+}
+
+// mergeIntervals sorts intervals and coalesces any that touch or overlap,
+// the way MySQL normalizes a GTID set.
+func mergeIntervals(intervals []GTIDInterval) []GTIDInterval {
+	if len(intervals) == 0 {
+		return intervals
+	}
+	sort.Sort(intervalsByStart(intervals))
+	merged := []GTIDInterval{intervals[0]}
+	for _, current := range intervals[1:] {
+		last := &merged[len(merged)-1]
+		if current.Start <= last.End+1 {
+			if current.End > last.End {
+				last.End = current.End
+			}
+			continue
+		}
+		merged = append(merged, current)
+	}
+	return merged
+}
+
+// String re-serializes the set into the canonical "uuid:1-100" form, with
+// UUIDs sorted for deterministic output.
+func (this GTIDSet) String() string {
+	uuids := make([]string, 0, len(this))
+	for uuid := range this {
+		uuids = append(uuids, uuid)
+	}
+	sort.Strings(uuids)
+
+	uuidSets := make([]string, 0, len(uuids))
+	for _, uuid := range uuids {
+		intervalStrings := make([]string, len(this[uuid]))
+		for i, interval := range this[uuid] {
+			intervalStrings[i] = interval.String()
+		}
+		uuidSets = append(uuidSets, fmt.Sprintf("%s:%s", uuid, strings.Join(intervalStrings, ":")))
+	}
+	return strings.Join(uuidSets, ",")
+}
+
+func (this GTIDSet) IsEmpty() bool {
+	return len(this) == 0
+}
+
+// Union returns the set of transactions present in either this or other.
+func (this GTIDSet) Union(other GTIDSet) GTIDSet {
+	result := GTIDSet{}
+	for uuid, intervals := range this {
+		result[uuid] = mergeIntervals(append([]GTIDInterval{}, intervals...))
+	}
+	for uuid, intervals := range other {
+		result[uuid] = mergeIntervals(append(result[uuid], intervals...))
+	}
+	return result
+}
+
+// Intersect returns the set of transactions present in both this and other.
+func (this GTIDSet) Intersect(other GTIDSet) GTIDSet {
+	result := GTIDSet{}
+	for uuid, intervals := range this {
+		otherIntervals, ok := other[uuid]
+		if !ok {
+			continue
+		}
+		var intersected []GTIDInterval
+		for _, interval := range intervals {
+			for _, otherInterval := range otherIntervals {
+				start := maxInt64(interval.Start, otherInterval.Start)
+				end := minInt64(interval.End, otherInterval.End)
+				if start <= end {
+					intersected = append(intersected, GTIDInterval{Start: start, End: end})
+				}
+			}
+		}
+		if len(intersected) > 0 {
+			result[uuid] = mergeIntervals(intersected)
+		}
+	}
+	return result
+}
+
+// Subtract returns the transactions present in this but not in other.
+func (this GTIDSet) Subtract(other GTIDSet) GTIDSet {
+	result := GTIDSet{}
+	for uuid, intervals := range this {
+		otherIntervals := other[uuid]
+		remaining := append([]GTIDInterval{}, intervals...)
+		for _, otherInterval := range otherIntervals {
+			remaining = subtractInterval(remaining, otherInterval)
+		}
+		if len(remaining) > 0 {
+			result[uuid] = remaining
+		}
+	}
+	return result
+}
+
+func subtractInterval(intervals []GTIDInterval, subtrahend GTIDInterval) []GTIDInterval {
+	result := []GTIDInterval{}
+	for _, interval := range intervals {
+		if subtrahend.End < interval.Start || subtrahend.Start > interval.End {
+			result = append(result, interval)
+			continue
+		}
+		if subtrahend.Start > interval.Start {
+			result = append(result, GTIDInterval{Start: interval.Start, End: subtrahend.Start - 1})
+		}
+		if subtrahend.End < interval.End {
+			result = append(result, GTIDInterval{Start: subtrahend.End + 1, End: interval.End})
+		}
+	}
+	return result
+}
+
+// IsSubsetOf returns true when every transaction in this set is also present in other.
+func (this GTIDSet) IsSubsetOf(other GTIDSet) bool {
+	for uuid, intervals := range this {
+		otherIntervals, ok := other[uuid]
+		if !ok {
+			if len(intervals) > 0 {
+				return false
+			}
+			continue
+		}
+		for _, interval := range intervals {
+			if !intervalCoveredBy(interval, otherIntervals) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func intervalCoveredBy(interval GTIDInterval, by []GTIDInterval) bool {
+	remaining := []GTIDInterval{interval}
+	for _, coveringInterval := range by {
+		remaining = subtractInterval(remaining, coveringInterval)
+		if len(remaining) == 0 {
+			return true
+		}
+	}
+	return len(remaining) == 0
+}
+
+// SmallerThan reports whether this set is a strict subset of other. The
+// second return value is false when the two sets are incomparable (neither
+// is a subset of the other), mirroring BinlogCoordinates.SmallerThan's
+// file/position ordering for the GTID world.
+func (this GTIDSet) SmallerThan(other GTIDSet) (smaller bool, ok bool) {
+	if this.String() == other.String() {
+		return false, true
+	}
+	if this.IsSubsetOf(other) {
+		return true, true
+	}
+	if other.IsSubsetOf(this) {
+		return false, true
+	}
+	return false, false
+}
+
+func maxInt64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,93 @@
+package inst
+
+import (
+	"testing"
+)
+
+func TestParseGTIDSetAndString(t *testing.T) {
+	gtidSet, err := ParseGTIDSet("uuid1:1-100,uuid2:1-50:60-70")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(gtidSet["uuid1"]) != 1 || gtidSet["uuid1"][0] != (GTIDInterval{Start: 1, End: 100}) {
+		t.Fatalf("unexpected uuid1 intervals: %+v", gtidSet["uuid1"])
+	}
+	if len(gtidSet["uuid2"]) != 2 {
+		t.Fatalf("unexpected uuid2 intervals: %+v", gtidSet["uuid2"])
+	}
+	if gtidSet.String() != "uuid1:1-100,uuid2:1-50:60-70" {
+		t.Fatalf("unexpected serialization: %s", gtidSet.String())
+	}
+}
+
+func TestParseGTIDSetMergesTouchingIntervals(t *testing.T) {
+	gtidSet, err := ParseGTIDSet("uuid1:1-50:51-100")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(gtidSet["uuid1"]) != 1 || gtidSet["uuid1"][0] != (GTIDInterval{Start: 1, End: 100}) {
+		t.Fatalf("expected touching intervals to merge, got %+v", gtidSet["uuid1"])
+	}
+}
+
+func TestParseGTIDSetMalformed(t *testing.T) {
+	if _, err := ParseGTIDSet("uuid1"); err == nil {
+		t.Fatalf("expected error for malformed set")
+	}
+	if _, err := ParseGTIDSet("uuid1:abc"); err == nil {
+		t.Fatalf("expected error for malformed interval")
+	}
+}
+
+func TestGTIDSetUnionIntersectSubtract(t *testing.T) {
+	a, _ := ParseGTIDSet("uuid1:1-100")
+	b, _ := ParseGTIDSet("uuid1:50-150")
+
+	union := a.Union(b)
+	if union.String() != "uuid1:1-150" {
+		t.Fatalf("unexpected union: %s", union.String())
+	}
+
+	intersect := a.Intersect(b)
+	if intersect.String() != "uuid1:50-100" {
+		t.Fatalf("unexpected intersect: %s", intersect.String())
+	}
+
+	subtract := a.Subtract(b)
+	if subtract.String() != "uuid1:1-49" {
+		t.Fatalf("unexpected subtract: %s", subtract.String())
+	}
+}
+
+func TestGTIDSetIsSubsetOf(t *testing.T) {
+	subset, _ := ParseGTIDSet("uuid1:1-50")
+	superset, _ := ParseGTIDSet("uuid1:1-100")
+
+	if !subset.IsSubsetOf(superset) {
+		t.Fatalf("expected uuid1:1-50 to be a subset of uuid1:1-100")
+	}
+	if superset.IsSubsetOf(subset) {
+		t.Fatalf("expected uuid1:1-100 to not be a subset of uuid1:1-50")
+	}
+
+	disjoint, _ := ParseGTIDSet("uuid2:1-10")
+	if disjoint.IsSubsetOf(superset) {
+		t.Fatalf("expected a set with an unrelated uuid to not be a subset")
+	}
+}
+
+func TestGTIDSetSmallerThan(t *testing.T) {
+	subset, _ := ParseGTIDSet("uuid1:1-50")
+	superset, _ := ParseGTIDSet("uuid1:1-100")
+	disjoint, _ := ParseGTIDSet("uuid1:200-300")
+
+	if smaller, ok := subset.SmallerThan(superset); !ok || !smaller {
+		t.Fatalf("expected subset to be smaller than superset, got smaller=%v ok=%v", smaller, ok)
+	}
+	if smaller, ok := superset.SmallerThan(subset); !ok || smaller {
+		t.Fatalf("expected superset to not be smaller than subset, got smaller=%v ok=%v", smaller, ok)
+	}
+	if _, ok := subset.SmallerThan(disjoint); ok {
+		t.Fatalf("expected disjoint sets to be incomparable")
+	}
+}
@@ -11,8 +11,24 @@ import (
 )
 
 
+// Resolver is the DNS lookup orchestrator needs when formalizing an
+// InstanceKey's hostname; it exists as a seam so tests can substitute a
+// fake and avoid real DNS traffic.
+type Resolver interface {
+	LookupCNAME(hostName string) (string, error)
+}
+
+type netResolver struct{}
+
+func (netResolver) LookupCNAME(hostName string) (string, error) {
+	return net.LookupCNAME(hostName)
+}
+
+// CNAMEResolver is the Resolver used by GetCNAME; replace it in tests.
+var CNAMEResolver Resolver = netResolver{}
+
 func GetCNAME(hostName string) (string, error) {
-	res, err := net.LookupCNAME(hostName);
+	res, err := CNAMEResolver.LookupCNAME(hostName);
 	if err != nil {
 		return hostName, err
 	}
@@ -25,32 +41,55 @@ type InstanceKey struct {
 	Port	 			int
 }
 
+// canonicalHostname is Hostname as Equals and comparisons should see it:
+// lower-cased, with any IPv6 brackets stripped, so that
+// "Host.Example.COM:3306" and "host.example.com:3306" are the same instance.
+func (this *InstanceKey) canonicalHostname() string {
+	return strings.ToLower(strings.Trim(this.Hostname, "[]"))
+}
+
+// Formalize resolves Hostname to its canonical CNAME. If Hostname is
+// already an IP literal there is no CNAME to resolve -- LookupCNAME would
+// just hand back the input, at the cost of a DNS round trip -- so that case
+// is skipped.
 func (this *InstanceKey) Formalize() *InstanceKey {
-	this.Hostname, _ = GetCNAME(this.Hostname) 
+	if net.ParseIP(this.Hostname) != nil {
+		return this
+	}
+	this.Hostname, _ = GetCNAME(this.Hostname)
 	return this
 }
 
 func (this *InstanceKey) Equals(other *InstanceKey) bool {
-	return this.Hostname == other.Hostname && this.Port == other.Port
+	return this.canonicalHostname() == other.canonicalHostname() && this.Port == other.Port
 }
 
 func (this *InstanceKey) IsValid() bool {
 	return len(this.Hostname) > 0 && this.Port > 0
 }
 
+// String renders the key in the form it was most likely parsed from,
+// re-bracketing an IPv6 literal hostname so the result is itself a valid
+// host:port that ParseInstanceKey can round-trip.
+func (this *InstanceKey) String() string {
+	if strings.Contains(this.Hostname, ":") {
+		return fmt.Sprintf("[%s]:%d", this.Hostname, this.Port)
+	}
+	return fmt.Sprintf("%s:%d", this.Hostname, this.Port)
+}
 
 func ParseInstanceKey(hostPort string) *InstanceKey {
-	tokens := strings.SplitN(hostPort, ":", 2)
-	if len(tokens) != 2 {
+	hostname, portToken, err := net.SplitHostPort(hostPort)
+	if err != nil {
 		return nil
 	}
-	port, err :=  strconv.Atoi(tokens[1])
+	port, err := strconv.Atoi(portToken)
 	if err != nil {
 		return nil
 	}
-	
+
     return &InstanceKey{
-    	Hostname: tokens[0], Port: port,
+    	Hostname: hostname, Port: port,
     }
 }
 
@@ -85,55 +124,82 @@ type Instance struct {
 	LogBinEnabled		bool
 	LogSlaveUpdatesEnabled	bool
 	SelfBinlogCoordinates	BinlogCoordinates
-	Master_Host			string
-	Master_Port			int
-	Slave_SQL_Running	bool
-	Slave_IO_Running	bool
-	ReadBinlogCoordinates	BinlogCoordinates
-	ExecBinlogCoordinates	BinlogCoordinates
-	SecondsBehindMaster	int
+	Channels			map[string]*ReplicationChannel
 	SlaveHosts			map[InstanceKey]bool
-	
+
 	IsUpToDate			bool
 }
 
 func NewInstance() *Instance {
     return &Instance{
+    	Channels: make(map[string]*ReplicationChannel),
     	SlaveHosts: make(map[InstanceKey]bool),
     }
 }
 
+// Channel returns the named replication channel, or nil if the instance has
+// no state for it (e.g. it is not a slave, or not a slave on that channel).
+func (this *Instance) Channel(channelName string) *ReplicationChannel {
+	return this.Channels[channelName]
+}
+
+// GetOrCreateChannel returns the named replication channel, creating an
+// empty one if this is the first time it's referenced -- used while
+// populating an Instance from SHOW SLAVE STATUS.
+func (this *Instance) GetOrCreateChannel(channelName string) *ReplicationChannel {
+	if this.Channels == nil {
+		this.Channels = make(map[string]*ReplicationChannel)
+	}
+	channel, ok := this.Channels[channelName]
+	if !ok {
+		channel = NewReplicationChannel(channelName)
+		this.Channels[channelName] = channel
+	}
+	return channel
+}
+
 func (this *Instance) Equals(other *Instance) bool {
 	return this.Key == other.Key
 }
 
-func (this *Instance) MajorVersion() []string {
-	return strings.Split(this.Version, ".")[:2]
+func (this *Instance) ParsedVersion() Version {
+	return ParseVersion(this.Version)
 }
 
+// IsSmallerMajorVersion reports whether this instance's version is lower
+// than other's. Despite the name it compares the full major.minor.patch
+// version (see Version.Compare), not only the major part; flavor is ignored.
 func (this *Instance) IsSmallerMajorVersion(other *Instance) bool {
-	thisMajorVersion := this.MajorVersion()
-	otherMajorVersion := other.MajorVersion()
-	for i := 0 ; i < len(thisMajorVersion); i++ {
-		this_token, _ := strconv.Atoi(thisMajorVersion[i])
-		other_token, _ := strconv.Atoi(otherMajorVersion[i])
-		if this_token < other_token {
-			return true
-		} 
-	}
-	return false
+	return this.ParsedVersion().Smaller(other.ParsedVersion())
+}
+
+// IsSlaveForChannel returns whether the instance is configured as a slave on
+// the given channel; empty string is the default (single-source) channel.
+func (this *Instance) IsSlaveForChannel(channelName string) bool {
+	channel := this.Channel(channelName)
+	return channel != nil && channel.IsSlave()
 }
 
 func (this *Instance) IsSlave() bool {
-	return this.Master_Host != "" && this.ReadBinlogCoordinates.LogFile != ""
+	return this.IsSlaveForChannel(DefaultChannelName)
+}
+
+func (this *Instance) SlaveRunningForChannel(channelName string) bool {
+	channel := this.Channel(channelName)
+	return channel != nil && channel.SlaveRunning()
 }
 
 func (this *Instance) SlaveRunning() bool {
-	return this.IsSlave() && this.Slave_SQL_Running && this.Slave_IO_Running
+	return this.SlaveRunningForChannel(DefaultChannelName)
+}
+
+func (this *Instance) SQLThreadUpToDateForChannel(channelName string) bool {
+	channel := this.Channel(channelName)
+	return channel != nil && channel.SQLThreadUpToDate()
 }
 
 func (this *Instance) SQLThreadUpToDate() bool {
-	return this.ReadBinlogCoordinates.Equals(&this.ExecBinlogCoordinates)
+	return this.SQLThreadUpToDateForChannel(DefaultChannelName)
 }
 
 
@@ -141,8 +207,34 @@ func (this *Instance) AddSlaveKey(slaveKey *InstanceKey) {
 	this.SlaveHosts[*slaveKey] = true
 }
 
+// GetMasterInstanceKeyForChannel returns the master this instance replicates
+// from on the given channel, or an empty key if it has no such channel.
+func (this *Instance) GetMasterInstanceKeyForChannel(channelName string) *InstanceKey {
+	channel := this.Channel(channelName)
+	if channel == nil {
+		return &InstanceKey{}
+	}
+	return channel.GetMasterInstanceKey()
+}
+
 func (this *Instance) GetMasterInstanceKey() *InstanceKey {
-	return &InstanceKey{Hostname: this.Master_Host, Port: this.Master_Port}
+	return this.GetMasterInstanceKeyForChannel(DefaultChannelName)
+}
+
+// GetMasterInstanceKeys returns the masters this instance replicates from
+// across all of its channels, e.g. when using MySQL 5.7 multi-source
+// replication.
+func (this *Instance) GetMasterInstanceKeys() []InstanceKey {
+	res := []InstanceKey{}
+	for _, channel := range this.Channels {
+		if !channel.IsSlave() {
+			// an empty placeholder channel (e.g. from GetOrCreateChannel)
+			// that was never populated with a master
+			continue
+		}
+		res = append(res, *channel.GetMasterInstanceKey())
+	}
+	return res
 }
 
 func (this *Instance) GetSlaveInstanceKeys() []InstanceKey {
@@ -174,23 +266,55 @@ func (this *Instance) ReadSlaveHostsFromJson(jsonString string) error {
 }
 
 
+// IsSlaveOfForChannel returns whether this instance replicates from master
+// on the given channel.
+func (this *Instance) IsSlaveOfForChannel(master *Instance, channelName string) bool {
+	return this.GetMasterInstanceKeyForChannel(channelName).Equals(&master.Key)
+}
+
 func (this *Instance) IsSlaveOf(master *Instance) bool {
-	return this.GetMasterInstanceKey().Equals(&master.Key)
+	return this.IsSlaveOfForChannel(master, DefaultChannelName)
+}
+
+// IsSlaveOfAny returns whether this instance replicates from master on any
+// of its channels.
+func (this *Instance) IsSlaveOfAny(master *Instance) bool {
+	for channelName := range this.Channels {
+		if this.IsSlaveOfForChannel(master, channelName) {
+			return true
+		}
+	}
+	return false
 }
 
 func (this *Instance) IsMasterOf(slave *Instance) bool {
 	return slave.IsSlaveOf(this)
 }
 
-func (this *Instance) CanReplicateFrom(other *Instance) (bool, error) {
+// CanReplicateFrom answers whether this instance could (continue to, or
+// begin to) replicate from other on the given channel. channelName lets a
+// topology tool validate attaching a new upstream to one channel of a
+// multi-source slave without having to know about its other channels.
+func (this *Instance) CanReplicateFrom(other *Instance, channelName string) (bool, error) {
 	if !other.LogBinEnabled {
-		return false, errors.New(fmt.Sprintf("instance does not have binary logs enabled: %+v", other.Key)) 
+		return false, errors.New(fmt.Sprintf("instance does not have binary logs enabled: %+v", other.Key))
 	}
 	if !other.LogSlaveUpdatesEnabled {
-		return false, errors.New(fmt.Sprintf("instance does not have log_slave_updates enabled: %+v", other.Key)) 
+		return false, errors.New(fmt.Sprintf("instance does not have log_slave_updates enabled: %+v", other.Key))
 	}
-	if this.IsSmallerMajorVersion(other) {
-		return false, errors.New(fmt.Sprintf("instance %+v has version %s, which is lower than %s on %+v ", this.Key, this.Version, other.Version, other.Key)) 
+	thisVersion, otherVersion := this.ParsedVersion(), other.ParsedVersion()
+	if !thisVersion.ReplicationCompatibleFlavor(otherVersion) {
+		return false, errors.New(fmt.Sprintf("instance %+v is %s while %+v is %s; cannot replicate across flavors", this.Key, thisVersion.Flavor, other.Key, otherVersion.Flavor))
+	}
+	if thisVersion.Smaller(otherVersion) {
+		return false, errors.New(fmt.Sprintf("instance %+v has version %s, which is lower than %s on %+v ", this.Key, this.Version, other.Version, other.Key))
+	}
+	thisChannel, otherChannel := this.Channel(channelName), other.Channel(channelName)
+	if thisChannel != nil && otherChannel != nil && thisChannel.UsingGtid && otherChannel.UsingGtid {
+		missingGtidSet := thisChannel.ExecutedGtidSet.Subtract(otherChannel.PurgedGtidSet)
+		if !missingGtidSet.IsSubsetOf(otherChannel.ExecutedGtidSet) {
+			return false, errors.New(fmt.Sprintf("instance %+v has transactions not present on %+v: %+v", this.Key, other.Key, missingGtidSet.Subtract(otherChannel.ExecutedGtidSet).String()))
+		}
 	}
 	if this.LogBinEnabled && this.LogSlaveUpdatesEnabled {
 		if this.Binlog_format == "STATEMENT" && (other.Binlog_format == "ROW" || other.Binlog_format == "MIXED") {
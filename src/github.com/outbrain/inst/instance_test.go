@@ -0,0 +1,59 @@
+package inst
+
+import (
+	"testing"
+)
+
+func TestParseInstanceKeyIPv6(t *testing.T) {
+	key := ParseInstanceKey("[::1]:3306")
+	if key == nil {
+		t.Fatalf("expected a parsed key, got nil")
+	}
+	if key.Hostname != "::1" || key.Port != 3306 {
+		t.Fatalf("unexpected key: %+v", key)
+	}
+	if key.String() != "[::1]:3306" {
+		t.Fatalf("expected round-trippable String(), got %s", key.String())
+	}
+}
+
+func TestParseInstanceKeyHostname(t *testing.T) {
+	key := ParseInstanceKey("host.example.com:3306")
+	if key == nil {
+		t.Fatalf("expected a parsed key, got nil")
+	}
+	if key.Hostname != "host.example.com" || key.Port != 3306 {
+		t.Fatalf("unexpected key: %+v", key)
+	}
+	if key.String() != "host.example.com:3306" {
+		t.Fatalf("unexpected String(): %s", key.String())
+	}
+}
+
+func TestParseInstanceKeyInvalid(t *testing.T) {
+	if ParseInstanceKey("no-port-here") != nil {
+		t.Fatalf("expected nil for a host with no port")
+	}
+	if ParseInstanceKey("host:not-a-port") != nil {
+		t.Fatalf("expected nil for a non-numeric port")
+	}
+}
+
+func TestInstanceKeyEqualsIsCaseAndBracketInsensitive(t *testing.T) {
+	lower := &InstanceKey{Hostname: "host.example.com", Port: 3306}
+	upper := &InstanceKey{Hostname: "Host.Example.COM", Port: 3306}
+	if !lower.Equals(upper) {
+		t.Fatalf("expected hostnames to compare equal regardless of case")
+	}
+
+	bracketed := &InstanceKey{Hostname: "[::1]", Port: 3306}
+	unbracketed := &InstanceKey{Hostname: "::1", Port: 3306}
+	if !bracketed.Equals(unbracketed) {
+		t.Fatalf("expected bracketed and unbracketed IPv6 hostnames to compare equal")
+	}
+
+	differentPort := &InstanceKey{Hostname: "host.example.com", Port: 3307}
+	if lower.Equals(differentPort) {
+		t.Fatalf("expected different ports to compare unequal")
+	}
+}
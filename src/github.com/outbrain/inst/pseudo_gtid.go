@@ -0,0 +1,240 @@
+package inst
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// pseudoGTIDPrefix marks a statement as an orchestrator-injected hint
+// rather than application traffic. A periodic writer (not part of this
+// package -- it needs a live connection to the master) is expected to issue
+// a statement built by NewPseudoGTIDEntry().Statement() every few seconds,
+// so that every server downstream of that master carries the same
+// monotonically increasing, uniquely-identifiable marker in its binlog
+// stream.
+const pseudoGTIDPrefix = "pseudo_gtid_hint"
+
+var pseudoGTIDPattern = regexp.MustCompile("`" + pseudoGTIDPrefix + `_(\d+)_([0-9a-f]+)` + "`")
+
+// PseudoGTIDEntry is one injected hint: a timestamp for rough ordering plus
+// a random token that makes it unique even when two entries share a
+// timestamp.
+type PseudoGTIDEntry struct {
+	Timestamp	int64
+	Token		string
+}
+
+// NewPseudoGTIDEntry builds a fresh, unique entry for the given time. The
+// caller is responsible for actually executing Statement() against the
+// master so it lands in the binary log.
+func NewPseudoGTIDEntry(timestamp int64) (PseudoGTIDEntry, error) {
+	tokenBytes := make([]byte, 8)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return PseudoGTIDEntry{}, err
+	}
+	return PseudoGTIDEntry{
+		Timestamp: timestamp,
+		Token: fmt.Sprintf("%x", tokenBytes),
+	}, nil
+}
+
+// Statement is the DDL orchestrator injects into the master's binlog. A
+// `DROP VIEW IF EXISTS` is used because it is a no-op on a view that never
+// existed, is replicated as a statement (so it shows up verbatim rather
+// than as row events) and requires no cleanup.
+func (this PseudoGTIDEntry) Statement() string {
+	return fmt.Sprintf("DROP VIEW IF EXISTS `%s_%d_%s`", pseudoGTIDPrefix, this.Timestamp, this.Token)
+}
+
+// ParsePseudoGTIDStatement recovers the entry encoded by a prior call to
+// Statement, as found verbatim in a replicated binlog event.
+func ParsePseudoGTIDStatement(statement string) (entry PseudoGTIDEntry, ok bool) {
+	tokens := pseudoGTIDPattern.FindStringSubmatch(statement)
+	if tokens == nil {
+		return PseudoGTIDEntry{}, false
+	}
+	timestamp := int64(0)
+	fmt.Sscanf(tokens[1], "%d", &timestamp)
+	return PseudoGTIDEntry{Timestamp: timestamp, Token: tokens[2]}, true
+}
+
+// BinlogEvent is one statement/transaction-boundary event as read off a
+// server's relay or binary log, in the order the log stores it.
+type BinlogEvent struct {
+	Coordinates	BinlogCoordinates
+	Statement	string
+}
+
+// BinlogEventStream is the seam between this package's pseudo-GTID matching
+// algorithm and however orchestrator actually talks to a server's logs
+// (mysqlbinlog, SHOW BINLOG EVENTS, ...). This package only depends on the
+// interface; a live implementation belongs next to whatever already owns
+// the MySQL connection pool.
+type BinlogEventStream interface {
+	// ScanBackward walks events at or before `from`, most recent first,
+	// invoking visit for each. `from` itself is included -- callers that
+	// want "the latest marker applied so far" pass their current position
+	// and must see a marker sitting exactly there. It stops when visit
+	// returns false, or the start of the available log is reached.
+	ScanBackward(from BinlogCoordinates, visit func(BinlogEvent) (keepGoing bool)) error
+
+	// ScanForward walks events strictly after `from`, in log order,
+	// invoking visit for each. It stops when visit returns false, or the
+	// end of the available log is reached.
+	ScanForward(from BinlogCoordinates, visit func(BinlogEvent) (keepGoing bool)) error
+}
+
+// ErrPseudoGTIDNotFound is returned when a pseudo-GTID token could not be
+// located on a server's logs -- either because that server never received
+// it (it is too far behind, or purged the relevant logs), or because the
+// originating master never produced one.
+var ErrPseudoGTIDNotFound = errors.New("inst: pseudo-GTID entry not found")
+
+// latestPseudoGTIDEntry scans backward from, and including, `upTo` for the
+// most recent pseudo-GTID statement and the coordinates it was found at --
+// i.e. the latest marker in the stream up to and including where it
+// currently stands.
+func latestPseudoGTIDEntry(stream BinlogEventStream, upTo BinlogCoordinates) (PseudoGTIDEntry, BinlogCoordinates, error) {
+	var found PseudoGTIDEntry
+	var foundAt BinlogCoordinates
+	err := stream.ScanBackward(upTo, func(event BinlogEvent) bool {
+		entry, ok := ParsePseudoGTIDStatement(event.Statement)
+		if !ok {
+			return true
+		}
+		found, foundAt = entry, event.Coordinates
+		return false
+	})
+	if err != nil {
+		return PseudoGTIDEntry{}, BinlogCoordinates{}, err
+	}
+	if found.Token == "" {
+		return PseudoGTIDEntry{}, BinlogCoordinates{}, ErrPseudoGTIDNotFound
+	}
+	return found, foundAt, nil
+}
+
+// locateToken scans forward on stream for the exact pseudo-GTID entry
+// identified by token, returning the coordinates it was found at.
+func locateToken(stream BinlogEventStream, token string) (BinlogCoordinates, error) {
+	var found BinlogCoordinates
+	var ok bool
+	err := stream.ScanForward(BinlogCoordinates{}, func(event BinlogEvent) bool {
+		entry, isPseudoGTID := ParsePseudoGTIDStatement(event.Statement)
+		if !isPseudoGTID || entry.Token != token {
+			return true
+		}
+		found, ok = event.Coordinates, true
+		return false
+	})
+	if err != nil {
+		return BinlogCoordinates{}, err
+	}
+	if !ok {
+		return BinlogCoordinates{}, ErrPseudoGTIDNotFound
+	}
+	return found, nil
+}
+
+// matchCoordinatesByPseudoGTID implements the core of MatchBelow: given the
+// slave's own applied-event stream and the candidate's binlog stream, it
+// finds the slave's most recent pseudo-GTID marker, locates that same
+// marker on the candidate, then replays both streams forward in lockstep
+// -- event by event, by statement text -- counting the events between the
+// marker and the slave's current execution position so the same offset can
+// be applied on the candidate's side. It fails cleanly (ErrPseudoGTIDNotFound)
+// if the candidate never saw the marker, meaning it is too far behind or has
+// purged the relevant logs.
+func matchCoordinatesByPseudoGTID(slaveStream, candidateStream BinlogEventStream, slaveExecCoordinates BinlogCoordinates) (*BinlogCoordinates, error) {
+	entry, markerOnSlave, err := latestPseudoGTIDEntry(slaveStream, slaveExecCoordinates)
+	if err != nil {
+		return nil, err
+	}
+
+	markerOnCandidate, err := locateToken(candidateStream, entry.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	// Collect the statements the slave applied between the marker and its
+	// current position; these are the events we must walk forward past on
+	// the candidate to land on the equivalent coordinates.
+	var eventsAfterMarker []string
+	if err := slaveStream.ScanForward(markerOnSlave, func(event BinlogEvent) bool {
+		if slaveExecCoordinates.SmallerThan(&event.Coordinates) {
+			return false
+		}
+		eventsAfterMarker = append(eventsAfterMarker, event.Statement)
+		return true
+	}); err != nil {
+		return nil, err
+	}
+
+	matched := markerOnCandidate
+	matchedCount := 0
+	if err := candidateStream.ScanForward(markerOnCandidate, func(event BinlogEvent) bool {
+		if matchedCount >= len(eventsAfterMarker) {
+			return false
+		}
+		if event.Statement != eventsAfterMarker[matchedCount] {
+			return false
+		}
+		matched = event.Coordinates
+		matchedCount++
+		return true
+	}); err != nil {
+		return nil, err
+	}
+	if matchedCount != len(eventsAfterMarker) {
+		return nil, errors.New(fmt.Sprintf("inst: candidate's binlog diverges from slave's applied events %d statements after pseudo-GTID marker %s", matchedCount, entry.Token))
+	}
+	return &matched, nil
+}
+
+// BinlogStreamProvider opens the live BinlogEventStream backing an
+// Instance's relay log (when acting as a slave) or binary log (when acting
+// as a master/candidate). This package ships the matching algorithm only;
+// a real implementation lives alongside whatever already owns orchestrator's
+// MySQL connections and is expected to set DefaultBinlogStreamProvider.
+type BinlogStreamProvider interface {
+	OpenAppliedEventStream(instance *Instance) (BinlogEventStream, error)
+	OpenBinaryEventStream(instance *Instance) (BinlogEventStream, error)
+}
+
+// DefaultBinlogStreamProvider is nil until something wires up real binlog
+// access; MatchBelow and Match return an error rather than panic until then.
+var DefaultBinlogStreamProvider BinlogStreamProvider
+
+// MatchBelow computes the BinlogCoordinates on newMaster's binary log that
+// correspond to slave's current ExecBinlogCoordinates, using the pseudo-GTID
+// hints both servers received from their current common master. This lets a
+// topology recovery move slave under newMaster even though neither GTID nor
+// a shared file/position scheme is available.
+func MatchBelow(slave *Instance, newMaster *Instance) (*BinlogCoordinates, error) {
+	if DefaultBinlogStreamProvider == nil {
+		return nil, errors.New("inst: no BinlogStreamProvider configured; pseudo-GTID matching requires live binlog access")
+	}
+	slaveChannel := slave.Channel(DefaultChannelName)
+	if slaveChannel == nil {
+		return nil, errors.New(fmt.Sprintf("inst: %+v is not a slave", slave.Key))
+	}
+	slaveStream, err := DefaultBinlogStreamProvider.OpenAppliedEventStream(slave)
+	if err != nil {
+		return nil, err
+	}
+	candidateStream, err := DefaultBinlogStreamProvider.OpenBinaryEventStream(newMaster)
+	if err != nil {
+		return nil, err
+	}
+	return matchCoordinatesByPseudoGTID(slaveStream, candidateStream, slaveChannel.ExecBinlogCoordinates)
+}
+
+// Match finds the BinlogCoordinates on slaveB's master-facing binary log
+// (i.e. treating slaveB as a would-be master) that correspond to slaveA's
+// current execution position, so the two siblings can be compared or
+// slaveA can be repointed below slaveB.
+func Match(slaveA *Instance, slaveB *Instance) (*BinlogCoordinates, error) {
+	return MatchBelow(slaveA, slaveB)
+}
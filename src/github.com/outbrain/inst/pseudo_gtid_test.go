@@ -0,0 +1,121 @@
+package inst
+
+import (
+	"testing"
+)
+
+// fakeEventStream is an in-memory BinlogEventStream over a fixed,
+// coordinate-ordered list of events, standing in for a real mysqlbinlog-backed
+// implementation.
+type fakeEventStream struct {
+	events []BinlogEvent
+}
+
+func (this *fakeEventStream) ScanBackward(from BinlogCoordinates, visit func(BinlogEvent) bool) error {
+	for i := len(this.events) - 1; i >= 0; i-- {
+		event := this.events[i]
+		if from.SmallerThan(&event.Coordinates) {
+			continue
+		}
+		if !visit(event) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (this *fakeEventStream) ScanForward(from BinlogCoordinates, visit func(BinlogEvent) bool) error {
+	for _, event := range this.events {
+		if !from.SmallerThan(&event.Coordinates) {
+			continue
+		}
+		if !visit(event) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func coords(pos int64) BinlogCoordinates {
+	return BinlogCoordinates{LogFile: "mysql-bin.000001", LogPos: pos}
+}
+
+func TestPseudoGTIDStatementRoundTrip(t *testing.T) {
+	entry, err := NewPseudoGTIDEntry(12345)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parsed, ok := ParsePseudoGTIDStatement(entry.Statement())
+	if !ok {
+		t.Fatalf("expected to parse back a generated statement")
+	}
+	if parsed.Timestamp != entry.Timestamp || parsed.Token != entry.Token {
+		t.Fatalf("round-tripped entry %+v does not match original %+v", parsed, entry)
+	}
+}
+
+func TestLatestPseudoGTIDEntryIncludesMarkerAtExactPosition(t *testing.T) {
+	entry, _ := NewPseudoGTIDEntry(1)
+	stream := &fakeEventStream{events: []BinlogEvent{
+		{Coordinates: coords(100), Statement: entry.Statement()},
+	}}
+
+	found, foundAt, err := latestPseudoGTIDEntry(stream, coords(100))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if found.Token != entry.Token {
+		t.Fatalf("expected to find the marker sitting exactly at the scan position, got %+v", found)
+	}
+	if !foundAt.Equals(&BinlogCoordinates{LogFile: "mysql-bin.000001", LogPos: 100}) {
+		t.Fatalf("unexpected coordinates: %+v", foundAt)
+	}
+}
+
+func TestLatestPseudoGTIDEntryNotFound(t *testing.T) {
+	stream := &fakeEventStream{events: []BinlogEvent{
+		{Coordinates: coords(100), Statement: "INSERT INTO t VALUES (1)"},
+	}}
+	if _, _, err := latestPseudoGTIDEntry(stream, coords(100)); err != ErrPseudoGTIDNotFound {
+		t.Fatalf("expected ErrPseudoGTIDNotFound, got %v", err)
+	}
+}
+
+func TestMatchCoordinatesByPseudoGTID(t *testing.T) {
+	entry, _ := NewPseudoGTIDEntry(1)
+	marker := entry.Statement()
+
+	slaveStream := &fakeEventStream{events: []BinlogEvent{
+		{Coordinates: coords(100), Statement: marker},
+		{Coordinates: coords(200), Statement: "INSERT INTO t VALUES (1)"},
+		{Coordinates: coords(300), Statement: "INSERT INTO t VALUES (2)"},
+	}}
+	candidateStream := &fakeEventStream{events: []BinlogEvent{
+		{Coordinates: coords(500), Statement: marker},
+		{Coordinates: coords(650), Statement: "INSERT INTO t VALUES (1)"},
+		{Coordinates: coords(800), Statement: "INSERT INTO t VALUES (2)"},
+		{Coordinates: coords(900), Statement: "INSERT INTO t VALUES (3)"},
+	}}
+
+	matched, err := matchCoordinatesByPseudoGTID(slaveStream, candidateStream, coords(300))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if matched.LogPos != 800 {
+		t.Fatalf("expected to land on the candidate's second applied statement (pos 800), got %+v", matched)
+	}
+}
+
+func TestMatchCoordinatesByPseudoGTIDCandidateMissingToken(t *testing.T) {
+	entry, _ := NewPseudoGTIDEntry(1)
+	slaveStream := &fakeEventStream{events: []BinlogEvent{
+		{Coordinates: coords(100), Statement: entry.Statement()},
+	}}
+	candidateStream := &fakeEventStream{events: []BinlogEvent{
+		{Coordinates: coords(500), Statement: "INSERT INTO t VALUES (1)"},
+	}}
+
+	if _, err := matchCoordinatesByPseudoGTID(slaveStream, candidateStream, coords(100)); err != ErrPseudoGTIDNotFound {
+		t.Fatalf("expected ErrPseudoGTIDNotFound when candidate never saw the marker, got %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package inst
+
+// DefaultChannelName is the channel an Instance's replication state lives
+// under when the server has no name for it -- i.e. single-source
+// replication on MySQL < 5.7 or MariaDB, where there is only ever one
+// master and `CHANGE MASTER TO ... FOR CHANNEL` was never used.
+const DefaultChannelName = ""
+
+// ReplicationChannel holds everything orchestrator tracks about a slave's
+// relationship to a single master. MySQL 5.7+ multi-source replication lets
+// a slave run several of these side by side, each independently started,
+// stopped and monitored; pre-5.7 servers and MariaDB always have exactly
+// one, stored under DefaultChannelName.
+type ReplicationChannel struct {
+	ChannelName				string
+	Master_Host				string
+	Master_Port				int
+	Slave_SQL_Running		bool
+	Slave_IO_Running		bool
+	ReadBinlogCoordinates	BinlogCoordinates
+	ExecBinlogCoordinates	BinlogCoordinates
+	SecondsBehindMaster		int
+
+	UsingGtid			bool
+	ExecutedGtidSet		GTIDSet
+	PurgedGtidSet		GTIDSet
+	RetrievedGtidSet	GTIDSet
+}
+
+func NewReplicationChannel(channelName string) *ReplicationChannel {
+	return &ReplicationChannel{
+		ChannelName: channelName,
+		ExecutedGtidSet: GTIDSet{},
+		PurgedGtidSet: GTIDSet{},
+		RetrievedGtidSet: GTIDSet{},
+	}
+}
+
+func (this *ReplicationChannel) GetMasterInstanceKey() *InstanceKey {
+	return &InstanceKey{Hostname: this.Master_Host, Port: this.Master_Port}
+}
+
+func (this *ReplicationChannel) IsSlave() bool {
+	return this.Master_Host != "" && this.ReadBinlogCoordinates.LogFile != ""
+}
+
+func (this *ReplicationChannel) SlaveRunning() bool {
+	return this.IsSlave() && this.Slave_SQL_Running && this.Slave_IO_Running
+}
+
+func (this *ReplicationChannel) SQLThreadUpToDate() bool {
+	if this.UsingGtid {
+		return this.RetrievedGtidSet.IsSubsetOf(this.ExecutedGtidSet)
+	}
+	return this.ReadBinlogCoordinates.Equals(&this.ExecBinlogCoordinates)
+}
@@ -0,0 +1,108 @@
+package inst
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type VersionFlavor int
+
+const (
+	FlavorMySQL VersionFlavor = iota
+	FlavorPercona
+	FlavorMariaDB
+)
+
+func (this VersionFlavor) String() string {
+	switch this {
+	case FlavorPercona:
+		return "Percona"
+	case FlavorMariaDB:
+		return "MariaDB"
+	default:
+		return "MySQL"
+	}
+}
+
+var versionNumbersPattern = regexp.MustCompile(`^(\d+)(?:\.(\d+))?(?:\.(\d+))?`)
+
+// Version is a parsed `SELECT VERSION()` string such as "5.7.30-log" or
+// "10.3.14-MariaDB", split into comparable numeric components plus the
+// server flavor, since "greater version number" and "compatible flavor" are
+// two different questions.
+type Version struct {
+	Major	int
+	Minor	int
+	Patch	int
+	Flavor	VersionFlavor
+	Raw		string
+}
+
+// ParseVersion parses the raw VERSION() string. Missing components default
+// to zero and an unparseable string yields zero Major, Minor and Patch, so
+// this never fails outright; Raw always holds the input and Flavor is still
+// detected from it.
+func ParseVersion(raw string) Version {
+	version := Version{Raw: raw, Flavor: FlavorMySQL}
+	if strings.Contains(raw, "MariaDB") {
+		version.Flavor = FlavorMariaDB
+	} else if strings.Contains(raw, "Percona") {
+		version.Flavor = FlavorPercona
+	}
+
+	tokens := versionNumbersPattern.FindStringSubmatch(raw)
+	if tokens == nil {
+		return version
+	}
+	version.Major, _ = strconv.Atoi(tokens[1])
+	if tokens[2] != "" {
+		version.Minor, _ = strconv.Atoi(tokens[2])
+	}
+	if tokens[3] != "" {
+		version.Patch, _ = strconv.Atoi(tokens[3])
+	}
+	return version
+}
+
+// Compare returns -1, 0 or 1 as this version is numerically smaller than,
+// equal to, or greater than other, comparing major/minor/patch in turn.
+// It does not consider Flavor: a MariaDB 10.3 and a MySQL 5.7 compare as
+// "10.3 > 5.7" even though the two are not really ordered with respect to
+// one another -- see ReplicationCompatibleFlavor for that question.
+func (this Version) Compare(other Version) int {
+	if this.Major != other.Major {
+		return compareInt(this.Major, other.Major)
+	}
+	if this.Minor != other.Minor {
+		return compareInt(this.Minor, other.Minor)
+	}
+	return compareInt(this.Patch, other.Patch)
+}
+
+// ReplicationCompatibleFlavor reports whether this and other's flavors can
+// speak to one another's replication protocol at all. Percona Server is a
+// MySQL fork that keeps the stock binlog/protocol, so it is compatible with
+// MySQL; MariaDB is not (different GTID scheme, diverging protocol), so a
+// mismatch there is a real incompatibility rather than a version ordering.
+func (this Version) ReplicationCompatibleFlavor(other Version) bool {
+	if this.Flavor == other.Flavor {
+		return true
+	}
+	return this.Flavor != FlavorMariaDB && other.Flavor != FlavorMariaDB
+}
+
+func (this Version) Smaller(other Version) bool {
+	return this.Compare(other) < 0
+}
+
+func compareInt(this int, other int) int {
+	switch {
+	case this < other:
+		return -1
+	case this > other:
+		return 1
+	default:
+		return 0
+	}
+}
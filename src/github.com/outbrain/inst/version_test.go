@@ -0,0 +1,74 @@
+package inst
+
+import (
+	"testing"
+)
+
+func TestParseVersionSuffixes(t *testing.T) {
+	version := ParseVersion("5.7.30-log")
+	if version.Major != 5 || version.Minor != 7 || version.Patch != 30 {
+		t.Fatalf("expected 5.7.30, got %+v", version)
+	}
+	if version.Flavor != FlavorMySQL {
+		t.Fatalf("expected FlavorMySQL, got %v", version.Flavor)
+	}
+
+	mariadb := ParseVersion("10.3.14-MariaDB")
+	if mariadb.Major != 10 || mariadb.Minor != 3 || mariadb.Patch != 14 {
+		t.Fatalf("expected 10.3.14, got %+v", mariadb)
+	}
+	if mariadb.Flavor != FlavorMariaDB {
+		t.Fatalf("expected FlavorMariaDB, got %v", mariadb.Flavor)
+	}
+
+	percona := ParseVersion("5.7.30-33-Percona")
+	if percona.Major != 5 || percona.Minor != 7 || percona.Patch != 30 {
+		t.Fatalf("expected 5.7.30, got %+v", percona)
+	}
+	if percona.Flavor != FlavorPercona {
+		t.Fatalf("expected FlavorPercona, got %v", percona.Flavor)
+	}
+}
+
+func TestParseVersionMissingComponents(t *testing.T) {
+	version := ParseVersion("5.7")
+	if version.Major != 5 || version.Minor != 7 || version.Patch != 0 {
+		t.Fatalf("expected 5.7.0, got %+v", version)
+	}
+
+	version = ParseVersion("8")
+	if version.Major != 8 || version.Minor != 0 || version.Patch != 0 {
+		t.Fatalf("expected 8.0.0, got %+v", version)
+	}
+}
+
+func TestVersionComparePatchLevel(t *testing.T) {
+	older := ParseVersion("5.7.9-log")
+	newer := ParseVersion("5.7.30-log")
+	if older.Compare(newer) >= 0 {
+		t.Fatalf("expected 5.7.9 < 5.7.30")
+	}
+	if !older.Smaller(newer) {
+		t.Fatalf("expected Smaller(5.7.9, 5.7.30) to be true")
+	}
+	if newer.Smaller(older) {
+		t.Fatalf("expected Smaller(5.7.30, 5.7.9) to be false")
+	}
+}
+
+func TestVersionCompareCrossFlavor(t *testing.T) {
+	mariadb := ParseVersion("10.3.14-MariaDB")
+	mysql := ParseVersion("5.7.30-log")
+
+	if mariadb.Compare(mysql) <= 0 {
+		t.Fatalf("expected 10.3.14 > 5.7.30 numerically")
+	}
+	if mariadb.ReplicationCompatibleFlavor(mysql) {
+		t.Fatalf("expected MariaDB and MySQL to be flagged as replication-incompatible flavors")
+	}
+
+	percona := ParseVersion("5.7.30-33-Percona")
+	if !percona.ReplicationCompatibleFlavor(mysql) {
+		t.Fatalf("expected Percona and MySQL to be replication-compatible flavors")
+	}
+}